@@ -0,0 +1,211 @@
+package web
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DirectoryBrowser serves an HTML directory listing for a file system,
+// delegating to FileStore.SendFile for anything that is not a directory.
+type DirectoryBrowser struct {
+	store *FileStore
+
+	// Prefix is the URL prefix DirectoryBrowser is mounted under. Serve
+	// itself takes an already-resolved file system path, like
+	// FileStore.SendFile, and does not touch Prefix; it is ServeHTTP that
+	// strips Prefix from the request path before calling Serve. Either
+	// way, Prefix is prepended when building the displayed path in the
+	// rendered listing.
+	Prefix string
+
+	// Template renders directory listings. It receives a *BrowserListing
+	// as its data. If nil, a built-in template is used instead.
+	Template *template.Template
+
+	// IgnoreIndexes serves "index.html" instead of a listing when the
+	// directory being browsed contains one.
+	IgnoreIndexes bool
+
+	// IgnorePatterns excludes entries whose name matches one of these
+	// patterns (as interpreted by path.Match) from listings.
+	IgnorePatterns []string
+}
+
+func NewDirectoryBrowser(fsys fs.FS, prefix string) *DirectoryBrowser {
+	return &DirectoryBrowser{
+		store:  NewFileStore(fsys),
+		Prefix: prefix,
+	}
+}
+
+// BrowserEntry describes a single file or directory in a listing.
+type BrowserEntry struct {
+	Name      string
+	Size      int64
+	HumanSize string
+	ModTime   time.Time
+	IsDir     bool
+}
+
+// BrowserListing is the data passed to Template when rendering a listing.
+type BrowserListing struct {
+	Path    string
+	CanGoUp bool
+	Entries []BrowserEntry
+}
+
+// Serve answers the request by either sending the file at name or, if name
+// names a directory, rendering an HTML listing of its contents. As with
+// FileStore.SendFile, name is passed directly to the underlying file system
+// without sanitization.
+func (b *DirectoryBrowser) Serve(w http.ResponseWriter, r *http.Request, name string) error {
+	file, err := b.store.fsys.Open(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NewHTTPError(http.StatusNotFound)
+		}
+		return NewHTTPErrorWithInternalError(http.StatusNotFound, err)
+	}
+
+	info, err := file.Stat()
+	file.Close()
+	if err != nil {
+		return NewHTTPErrorWithInternalError(http.StatusInternalServerError, err)
+	}
+
+	if !info.IsDir() {
+		return b.store.SendFile(w, r, name)
+	}
+
+	if b.IgnoreIndexes {
+		indexName := path.Join(name, "index.html")
+		if indexFile, err := b.store.fsys.Open(indexName); err == nil {
+			indexFile.Close()
+			return b.store.SendFile(w, r, indexName)
+		}
+	}
+
+	return b.serveListing(w, r, name)
+}
+
+// ServeHTTP implements http.Handler by stripping Prefix from r.URL.Path and
+// passing the remainder to Serve. Mount it directly, e.g.
+// http.Handle("/static/", NewDirectoryBrowser(fsys, "/static")).
+func (b *DirectoryBrowser) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, b.Prefix)
+	name = strings.TrimPrefix(name, "/")
+	if name == "" {
+		name = "."
+	}
+
+	if err := b.Serve(w, r, name); err != nil {
+		DefaultErrorHandler(w, r, err)
+	}
+}
+
+func (b *DirectoryBrowser) serveListing(w http.ResponseWriter, r *http.Request, name string) error {
+	infos, err := fs.ReadDir(b.store.fsys, name)
+	if err != nil {
+		return NewHTTPErrorWithInternalError(http.StatusInternalServerError, err)
+	}
+
+	entries := make([]BrowserEntry, 0, len(infos))
+	for _, dirEntry := range infos {
+		if b.isIgnored(dirEntry.Name()) {
+			continue
+		}
+
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+
+		entries = append(entries, BrowserEntry{
+			Name:      dirEntry.Name(),
+			Size:      info.Size(),
+			HumanSize: humanSize(info.Size()),
+			ModTime:   info.ModTime(),
+			IsDir:     dirEntry.IsDir(),
+		})
+	}
+
+	sortEntries(entries, r.URL.Query().Get("sort"), r.URL.Query().Get("order"))
+
+	listing := &BrowserListing{
+		Path:    "/" + strings.Trim(b.Prefix+"/"+name, "/"),
+		CanGoUp: strings.Trim(name, "/") != "" && strings.Trim(name, "/") != ".",
+		Entries: entries,
+	}
+
+	tmpl := b.Template
+	if tmpl == nil {
+		tmpl = defaultBrowserTemplate
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	return tmpl.Execute(w, listing)
+}
+
+func (b *DirectoryBrowser) isIgnored(name string) bool {
+	for _, pattern := range b.IgnorePatterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func sortEntries(entries []BrowserEntry, by, order string) {
+	less := func(i, j int) bool {
+		switch by {
+		case "size":
+			return entries[i].Size < entries[j].Size
+		case "time":
+			return entries[i].ModTime.Before(entries[j].ModTime)
+		default:
+			return entries[i].Name < entries[j].Name
+		}
+	}
+	if order == "desc" {
+		wrapped := less
+		less = func(i, j int) bool { return wrapped(j, i) }
+	}
+	sort.SliceStable(entries, less)
+}
+
+func humanSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+var defaultBrowserTemplate = template.Must(template.New("directorybrowser").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Index of {{.Path}}</title></head>
+<body>
+<h1>Index of {{.Path}}</h1>
+<table>
+<thead><tr><th>Name</th><th>Size</th><th>Last Modified</th></tr></thead>
+<tbody>
+{{if .CanGoUp}}<tr><td><a href="../">../</a></td><td></td><td></td></tr>{{end}}
+{{range .Entries}}<tr><td><a href="{{.Name}}{{if .IsDir}}/{{end}}">{{.Name}}{{if .IsDir}}/{{end}}</a></td><td>{{if not .IsDir}}{{.HumanSize}}{{end}}</td><td>{{.ModTime.Format "2006-01-02 15:04:05"}}</td></tr>
+{{end}}
+</tbody>
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,78 @@
+package web
+
+import "testing"
+
+func TestSortEntries(t *testing.T) {
+	newEntries := func() []BrowserEntry {
+		return []BrowserEntry{
+			{Name: "b.txt", Size: 20},
+			{Name: "a.txt", Size: 30},
+			{Name: "c.txt", Size: 10},
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		by       string
+		order    string
+		expected []string
+	}{
+		{"name asc (default)", "", "", []string{"a.txt", "b.txt", "c.txt"}},
+		{"name desc", "", "desc", []string{"c.txt", "b.txt", "a.txt"}},
+		{"size asc", "size", "", []string{"c.txt", "b.txt", "a.txt"}},
+		{"size desc", "size", "desc", []string{"a.txt", "b.txt", "c.txt"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			entries := newEntries()
+			sortEntries(entries, tc.by, tc.order)
+			for i, e := range entries {
+				if e.Name != tc.expected[i] {
+					t.Errorf("index=%d actual=%s expected=%s", i, e.Name, tc.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHumanSize(t *testing.T) {
+	testCases := []struct {
+		size     int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{1024 * 1024, "1.0 MiB"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			actual := humanSize(tc.size)
+			if actual != tc.expected {
+				t.Errorf("actual=%s expected=%s", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestDirectoryBrowserIsIgnored(t *testing.T) {
+	b := &DirectoryBrowser{IgnorePatterns: []string{".*", "*.secret"}}
+
+	testCases := []struct {
+		name     string
+		expected bool
+	}{
+		{".git", true},
+		{"notes.secret", true},
+		{"index.html", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := b.isIgnored(tc.name)
+			if actual != tc.expected {
+				t.Errorf("actual=%v expected=%v", actual, tc.expected)
+			}
+		})
+	}
+}
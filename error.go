@@ -5,6 +5,20 @@ import (
 	"net/http"
 )
 
+// ErrorHandlerFunc writes an HTTP response for an error that occurred while
+// handling r. It is the type expected by Handler.OnError.
+type ErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, err error)
+
+// DefaultErrorHandler writes a terse error response derived from err's
+// *HTTPError.StatusCode, or 500 if err is not an *HTTPError.
+func DefaultErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	statusCode := http.StatusInternalServerError
+	if httpError, ok := err.(*HTTPError); ok {
+		statusCode = httpError.StatusCode
+	}
+	http.Error(w, err.Error(), statusCode)
+}
+
 type HTTPError struct {
 	StatusCode int
 	Internal   error
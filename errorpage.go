@@ -0,0 +1,138 @@
+package web
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// templateErrorLocation matches the "name:line" or "name:line:col" prefix
+// that text/template and html/template prepend to both parse and execution
+// errors, e.g. `template: foo.html:12:34: executing "foo.html" at <.Bar>: ...`.
+var templateErrorLocation = regexp.MustCompile(`^template:\s*([^:]+):(\d+)(?::(\d+))?:`)
+
+// parseTemplateError extracts the template name and source position from a
+// text/template or html/template error, if possible.
+func parseTemplateError(err error) (name string, line, col int, ok bool) {
+	match := templateErrorLocation.FindStringSubmatch(err.Error())
+	if match == nil {
+		return "", 0, 0, false
+	}
+	line, _ = strconv.Atoi(match[2])
+	if match[3] != "" {
+		col, _ = strconv.Atoi(match[3])
+	}
+	return match[1], line, col, true
+}
+
+type debugSourceLine struct {
+	Number    int
+	Text      string
+	Highlight bool
+}
+
+// sourceContext returns up to contextSize lines of source before and after
+// the given (1-based) line number, inclusive.
+func sourceContext(source string, line, contextSize int) []debugSourceLine {
+	lines := strings.Split(source, "\n")
+
+	start := line - contextSize
+	if start < 1 {
+		start = 1
+	}
+	end := line + contextSize
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	result := make([]debugSourceLine, 0, end-start+1)
+	for n := start; n <= end; n++ {
+		result = append(result, debugSourceLine{
+			Number:    n,
+			Text:      lines[n-1],
+			Highlight: n == line,
+		})
+	}
+	return result
+}
+
+type debugPage struct {
+	Method  string
+	Path    string
+	File    string
+	Line    int
+	Column  int
+	Message string
+	Context []debugSourceLine
+}
+
+// ErrorHandler is an ErrorHandlerFunc suitable for use as Handler.OnError.
+// When Debug is enabled and err is a template parse or execution error for
+// which source is available, it renders a diagnostic HTML page showing the
+// failing file with the offending line highlighted. Otherwise, and always
+// when Debug is false, it falls back to DefaultErrorHandler, so production
+// behavior is unchanged.
+func (r *Renderer) ErrorHandler(w http.ResponseWriter, req *http.Request, err error) {
+	if !r.Debug {
+		DefaultErrorHandler(w, req, err)
+		return
+	}
+
+	name, line, col, ok := parseTemplateError(err)
+	if !ok {
+		DefaultErrorHandler(w, req, err)
+		return
+	}
+
+	r.sourcesMutex.Lock()
+	source, haveSource := r.sources[name]
+	r.sourcesMutex.Unlock()
+	if !haveSource {
+		DefaultErrorHandler(w, req, err)
+		return
+	}
+
+	page := &debugPage{
+		Method:  req.Method,
+		Path:    req.URL.Path,
+		File:    name,
+		Line:    line,
+		Column:  col,
+		Message: err.Error(),
+		Context: sourceContext(source, line, 5),
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	if execErr := debugPageTemplate.Execute(w, page); execErr != nil {
+		log.Println("[renderer] error: failed to render debug error page:", execErr)
+	}
+}
+
+var debugPageTemplate = template.Must(template.New("errorpage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>Template Error</title>
+<style>
+body { font-family: monospace; background: #1e1e1e; color: #ddd; padding: 2em; }
+h1 { color: #f66; }
+.meta { color: #999; margin-bottom: 1em; }
+.line { white-space: pre; }
+.line .number { display: inline-block; width: 4em; color: #777; text-align: right; margin-right: 1em; }
+.line.highlight { background: #5a1e1e; }
+.line.highlight .number { color: #f99; }
+</style>
+</head>
+<body>
+<h1>{{.Message}}</h1>
+<p class="meta">{{.Method}} {{.Path}} &mdash; {{.File}}:{{.Line}}{{if .Column}}:{{.Column}}{{end}}</p>
+<div class="source">
+{{range .Context}}<div class="line{{if .Highlight}} highlight{{end}}"><span class="number">{{.Number}}</span>{{.Text}}</div>
+{{end}}
+</div>
+</body>
+</html>
+`))
@@ -0,0 +1,106 @@
+package web
+
+import (
+	"errors"
+	"testing"
+	texttemplate "text/template"
+)
+
+func TestParseTemplateError(t *testing.T) {
+	testCases := []struct {
+		name         string
+		err          error
+		expectedName string
+		expectedLine int
+		expectedCol  int
+		expectedOK   bool
+	}{
+		{
+			name:         "exec error with line and column",
+			err:          errors.New(`template: foo.html:12:34: executing "foo.html" at <.Bar>: nil pointer evaluating`),
+			expectedName: "foo.html",
+			expectedLine: 12,
+			expectedCol:  34,
+			expectedOK:   true,
+		},
+		{
+			name:         "parse error with line only",
+			err:          errors.New(`template: foo.html:3: unexpected "}" in operand`),
+			expectedName: "foo.html",
+			expectedLine: 3,
+			expectedCol:  0,
+			expectedOK:   true,
+		},
+		{
+			name:       "unrelated error",
+			err:        errors.New("permission denied"),
+			expectedOK: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, line, col, ok := parseTemplateError(tc.err)
+			if ok != tc.expectedOK {
+				t.Fatalf("ok actual=%v expected=%v", ok, tc.expectedOK)
+			}
+			if !ok {
+				return
+			}
+			if name != tc.expectedName || line != tc.expectedLine || col != tc.expectedCol {
+				t.Errorf("actual=%s:%d:%d expected=%s:%d:%d", name, line, col, tc.expectedName, tc.expectedLine, tc.expectedCol)
+			}
+		})
+	}
+}
+
+func TestParseTemplateErrorFromRealParseFailure(t *testing.T) {
+	_, err := texttemplate.New("bad.txt").Parse("line one\nline two {{ }}\nline three")
+	if err == nil {
+		t.Fatal("expected a parse error")
+	}
+
+	name, line, _, ok := parseTemplateError(err)
+	if !ok {
+		t.Fatalf("failed to parse location out of: %v", err)
+	}
+	if name != "bad.txt" || line != 2 {
+		t.Errorf("actual=%s:%d expected=bad.txt:2", name, line)
+	}
+}
+
+func TestSourceContext(t *testing.T) {
+	source := "one\ntwo\nthree\nfour\nfive"
+
+	testCases := []struct {
+		name        string
+		line        int
+		contextSize int
+		wantFirst   int
+		wantLast    int
+	}{
+		{"middle with room on both sides", 3, 1, 2, 4},
+		{"clipped at start of file", 1, 2, 1, 3},
+		{"clipped at end of file", 5, 2, 3, 5},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lines := sourceContext(source, tc.line, tc.contextSize)
+			if lines[0].Number != tc.wantFirst || lines[len(lines)-1].Number != tc.wantLast {
+				t.Fatalf("actual=[%d,%d] expected=[%d,%d]", lines[0].Number, lines[len(lines)-1].Number, tc.wantFirst, tc.wantLast)
+			}
+
+			highlighted := 0
+			for _, l := range lines {
+				if l.Highlight {
+					highlighted++
+					if l.Number != tc.line {
+						t.Errorf("highlighted line=%d, expected=%d", l.Number, tc.line)
+					}
+				}
+			}
+			if highlighted != 1 {
+				t.Errorf("expected exactly one highlighted line, got %d", highlighted)
+			}
+		})
+	}
+}
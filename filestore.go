@@ -7,8 +7,13 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"mime"
 	"net/http"
 	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -18,8 +23,20 @@ import (
 // ETags are cached by filename and updated when the modification time changes.
 type FileStore struct {
 	fsys       fs.FS
+	directory  string // set by NewFileStoreFromDirectory; required by Watch
 	etagsMutex sync.Mutex
 	etags      map[string]etagInfo
+
+	// PrecompressedAssets enables AssetServer mode: for every file served,
+	// FileStore looks for sibling files carrying a compression extension
+	// (".zst", ".br", ".gz") and serves whichever one the client accepts
+	// best, according to the Accept-Encoding header. The Content-Type and
+	// ETag are still derived from the uncompressed file, so the ETag stays
+	// stable no matter which encoding ends up on the wire.
+	PrecompressedAssets bool
+
+	variantsMutex sync.Mutex
+	variants      map[string]variantInfo
 }
 
 type etagInfo struct {
@@ -27,15 +44,86 @@ type etagInfo struct {
 	Tag     string
 }
 
+// variantInfo caches which pre-compressed siblings exist for a file, so
+// that most requests do not need to probe the file system for each
+// supported encoding.
+type variantInfo struct {
+	Files map[string]variantFile // encoding -> file
+}
+
+type variantFile struct {
+	Name    string
+	ModTime time.Time
+}
+
+// precompressedEncodings lists the encodings FileStore knows how to serve
+// from pre-compressed sibling files, ordered from most to least preferred
+// when the client accepts more than one of them equally.
+var precompressedEncodings = []string{"zstd", "br", "gzip"}
+
+var precompressedExtensions = map[string]string{
+	"zstd": ".zst",
+	"br":   ".br",
+	"gzip": ".gz",
+}
+
+// variantBaseName maps relPath to the key discoverVariants caches it
+// under: store.variants is keyed by the uncompressed asset name, so if
+// relPath itself carries a known compression extension (e.g. "foo.js.gz"
+// changed on disk), that extension is stripped to invalidate the cache
+// entry for "foo.js" rather than one that never existed.
+func variantBaseName(relPath string) string {
+	ext := path.Ext(relPath)
+	for _, known := range precompressedExtensions {
+		if ext == known {
+			return strings.TrimSuffix(relPath, known)
+		}
+	}
+	return relPath
+}
+
 func NewFileStore(fsys fs.FS) *FileStore {
 	return &FileStore{
-		fsys:  fsys,
-		etags: make(map[string]etagInfo),
+		fsys:     fsys,
+		etags:    make(map[string]etagInfo),
+		variants: make(map[string]variantInfo),
 	}
 }
 
 func NewFileStoreFromDirectory(dirname string) *FileStore {
-	return NewFileStore(os.DirFS(dirname))
+	store := NewFileStore(os.DirFS(dirname))
+	store.directory = dirname
+	return store
+}
+
+// Watch watches the store's backing directory (recursively) for changes,
+// invalidating cached ETags and pre-compressed variants as files change,
+// and notifying any clients connected to LiveReloadHandler. It only works
+// if the store was created with NewFileStoreFromDirectory. The returned
+// io.Closer stops watching and must be closed once the store is no longer
+// needed.
+func (store *FileStore) Watch() (io.Closer, error) {
+	if store.directory == "" {
+		return nil, fmt.Errorf("filestore: Watch requires a store created with NewFileStoreFromDirectory")
+	}
+
+	return watchDirectory(store.directory, func(name string) {
+		relPath, err := filepath.Rel(store.directory, name)
+		if err != nil {
+			return
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		store.etagsMutex.Lock()
+		delete(store.etags, relPath)
+		store.etagsMutex.Unlock()
+
+		store.variantsMutex.Lock()
+		delete(store.variants, variantBaseName(relPath))
+		store.variantsMutex.Unlock()
+
+		notifyLiveReload()
+	})
 }
 
 // SendFile answers the request by sending a file.
@@ -44,6 +132,9 @@ func NewFileStoreFromDirectory(dirname string) *FileStore {
 // Note that the filename is not sanitized in any way and passed directly to fsys.Open().
 // However, if you are using http.ServeMux, it should have already sanitized
 // the URL request path, so you can safely construct the filename from that.
+//
+// If PrecompressedAssets is enabled, SendFile may instead serve a
+// pre-compressed sibling of filename; see PrecompressedAssets for details.
 func (store *FileStore) SendFile(w http.ResponseWriter, r *http.Request, filename string) error {
 	file, err := store.fsys.Open(filename)
 	if err != nil {
@@ -104,6 +195,177 @@ func (store *FileStore) SendFile(w http.ResponseWriter, r *http.Request, filenam
 	// ETag is handled by ServeContent.
 	w.Header().Set("ETag", tagInfo.Tag)
 
+	if store.PrecompressedAssets {
+		if encoding, data, ok := store.openVariant(filename, r); ok {
+			w.Header().Set("Content-Encoding", encoding)
+			w.Header().Add("Vary", "Accept-Encoding")
+			ctype := mime.TypeByExtension(path.Ext(filename))
+			if ctype == "" {
+				// The variant's own bytes are compressed and would sniff as
+				// garbage; sniff the uncompressed file instead.
+				ctype = sniffContentType(reader)
+			}
+			w.Header().Set("Content-Type", ctype)
+			// Last-Modified, like ETag, is derived from the uncompressed
+			// file so it stays stable no matter which encoding is served.
+			http.ServeContent(w, r, info.Name(), info.ModTime(), bytes.NewReader(data))
+			return nil
+		}
+	}
+
 	http.ServeContent(w, r, info.Name(), info.ModTime(), reader)
 	return nil
 }
+
+// openVariant picks the best pre-compressed variant of filename that the
+// request accepts (per Accept-Encoding) and reads it into memory. It
+// returns ok == false if no variant exists or none is acceptable, in which
+// case SendFile falls back to serving the uncompressed file.
+func (store *FileStore) openVariant(filename string, r *http.Request) (encoding string, data []byte, ok bool) {
+	info := store.discoverVariants(filename)
+	if len(info.Files) == 0 {
+		return "", nil, false
+	}
+
+	qualities := parseAcceptEncodingQualities(r.Header.Get("Accept-Encoding"))
+	var chosenEncoding string
+	var chosenFile variantFile
+	chosenQuality := 0.0
+	for _, enc := range precompressedEncodings {
+		variant, exists := info.Files[enc]
+		if !exists {
+			continue
+		}
+		// Strictly greater, so equal q-values keep the earlier (preferred)
+		// encoding.
+		if q := acceptQuality(qualities, enc); q > chosenQuality {
+			chosenEncoding = enc
+			chosenFile = variant
+			chosenQuality = q
+		}
+	}
+	if chosenEncoding == "" {
+		return "", nil, false
+	}
+
+	file, err := store.fsys.Open(chosenFile.Name)
+	if err != nil {
+		// The variant disappeared since it was discovered; forget about it
+		// and let the caller fall back to the uncompressed file.
+		store.variantsMutex.Lock()
+		delete(store.variants, filename)
+		store.variantsMutex.Unlock()
+		return "", nil, false
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return "", nil, false
+	}
+	if !stat.ModTime().Equal(chosenFile.ModTime) {
+		// The variant changed since it was discovered; invalidate the
+		// cache entry so the next request rediscovers all variants.
+		store.variantsMutex.Lock()
+		delete(store.variants, filename)
+		store.variantsMutex.Unlock()
+	}
+
+	contents, err := io.ReadAll(file)
+	if err != nil {
+		return "", nil, false
+	}
+
+	return chosenEncoding, contents, true
+}
+
+// sniffContentType detects the content type of an already-open uncompressed
+// file from its leading bytes, the same way http.ServeContent would if no
+// Content-Type header were set. reader is left back at the start so it can
+// still serve the uncompressed fallback afterwards.
+func sniffContentType(reader io.ReadSeeker) string {
+	buf := make([]byte, 512)
+	n, _ := io.ReadFull(reader, buf)
+	reader.Seek(0, io.SeekStart)
+	return http.DetectContentType(buf[:n])
+}
+
+// discoverVariants finds the pre-compressed siblings of filename and caches
+// the result so that repeat requests do not need to probe the file system
+// for every supported encoding.
+func (store *FileStore) discoverVariants(filename string) variantInfo {
+	store.variantsMutex.Lock()
+	info, ok := store.variants[filename]
+	store.variantsMutex.Unlock()
+	if ok {
+		return info
+	}
+
+	files := make(map[string]variantFile)
+	for _, enc := range precompressedEncodings {
+		name := filename + precompressedExtensions[enc]
+		file, err := store.fsys.Open(name)
+		if err != nil {
+			continue
+		}
+		stat, err := file.Stat()
+		file.Close()
+		if err != nil || stat.IsDir() {
+			continue
+		}
+		files[enc] = variantFile{Name: name, ModTime: stat.ModTime()}
+	}
+
+	info = variantInfo{Files: files}
+
+	store.variantsMutex.Lock()
+	store.variants[filename] = info
+	store.variantsMutex.Unlock()
+
+	return info
+}
+
+// parseAcceptEncodingQualities parses an Accept-Encoding header into a map
+// of encoding name (lowercased) to q-value.
+func parseAcceptEncodingQualities(header string) map[string]float64 {
+	qualities := make(map[string]float64)
+	if header == "" {
+		return qualities
+	}
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name := part
+		q := 1.0
+		if i := strings.Index(part, ";"); i >= 0 {
+			name = strings.TrimSpace(part[:i])
+			if qs := strings.TrimSpace(part[i+1:]); strings.HasPrefix(qs, "q=") {
+				if v, err := strconv.ParseFloat(strings.TrimPrefix(qs, "q="), 64); err == nil {
+					q = v
+				}
+			}
+		}
+
+		qualities[strings.ToLower(name)] = q
+	}
+	return qualities
+}
+
+// acceptQuality returns the q-value the client assigned to encoding,
+// falling back to the "*" entry, and treats "identity" as implicitly
+// acceptable unless the header says otherwise.
+func acceptQuality(qualities map[string]float64, encoding string) float64 {
+	if q, ok := qualities[encoding]; ok {
+		return q
+	}
+	if q, ok := qualities["*"]; ok {
+		return q
+	}
+	if encoding == "identity" {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,115 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseAcceptEncodingQualities(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected map[string]float64
+	}{
+		{"", map[string]float64{}},
+		{"gzip", map[string]float64{"gzip": 1}},
+		{"gzip;q=0.5, br", map[string]float64{"gzip": 0.5, "br": 1}},
+		{"gzip;q=0", map[string]float64{"gzip": 0}},
+		{"*;q=0, gzip", map[string]float64{"*": 0, "gzip": 1}},
+		{"GZIP", map[string]float64{"gzip": 1}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			actual := parseAcceptEncodingQualities(tc.input)
+			if len(actual) != len(tc.expected) {
+				t.Fatalf("actual=%v expected=%v", actual, tc.expected)
+			}
+			for k, v := range tc.expected {
+				if actual[k] != v {
+					t.Errorf("key=%s actual=%v expected=%v", k, actual[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestAcceptQuality(t *testing.T) {
+	testCases := []struct {
+		name      string
+		qualities map[string]float64
+		encoding  string
+		expected  float64
+	}{
+		{"explicit", map[string]float64{"gzip": 0.5}, "gzip", 0.5},
+		{"q=0 excludes", map[string]float64{"gzip": 0}, "gzip", 0},
+		{"wildcard fallback", map[string]float64{"*": 0.3}, "br", 0.3},
+		{"q=0 wildcard excludes unlisted", map[string]float64{"*": 0}, "br", 0},
+		{"identity implicit unless excluded", map[string]float64{}, "identity", 1},
+		{"unlisted non-identity defaults to zero", map[string]float64{}, "br", 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := acceptQuality(tc.qualities, tc.encoding)
+			if actual != tc.expected {
+				t.Errorf("actual=%v expected=%v", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestFileStoreSendFileVariantUsesUncompressedMetadata(t *testing.T) {
+	dir := t.TempDir()
+
+	// "data" has no extension, so Content-Type can only come from sniffing;
+	// its gzip sibling is binary garbage that would sniff completely
+	// differently if FileStore mistakenly sniffed the compressed bytes.
+	uncompressed := []byte("plain text content\n")
+	if err := os.WriteFile(filepath.Join(dir, "data"), uncompressed, 0o644); err != nil {
+		t.Fatalf("failed to write data: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "data.gz"), []byte{0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00}, 0o644); err != nil {
+		t.Fatalf("failed to write data.gz: %v", err)
+	}
+
+	// Give the variant a different ModTime than the uncompressed file so a
+	// Last-Modified header copied from the variant would be detectable.
+	staleTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(filepath.Join(dir, "data.gz"), staleTime, staleTime); err != nil {
+		t.Fatalf("failed to set data.gz mtime: %v", err)
+	}
+
+	uncompressedInfo, err := os.Stat(filepath.Join(dir, "data"))
+	if err != nil {
+		t.Fatalf("failed to stat data: %v", err)
+	}
+
+	store := NewFileStoreFromDirectory(dir)
+	store.PrecompressedAssets = true
+
+	r := httptest.NewRequest(http.MethodGet, "/data", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	if err := store.SendFile(w, r, "data"); err != nil {
+		t.Fatalf("SendFile failed: %v", err)
+	}
+
+	if enc := w.Header().Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("expected gzip variant to be served, got Content-Encoding=%q", enc)
+	}
+
+	expectedCtype := http.DetectContentType(uncompressed)
+	if ctype := w.Header().Get("Content-Type"); ctype != expectedCtype {
+		t.Errorf("Content-Type actual=%q expected=%q (sniffed from compressed bytes instead of uncompressed?)", ctype, expectedCtype)
+	}
+
+	lastModified, err := http.ParseTime(w.Header().Get("Last-Modified"))
+	if err != nil {
+		t.Fatalf("failed to parse Last-Modified: %v", err)
+	}
+	if !lastModified.Equal(uncompressedInfo.ModTime().Truncate(time.Second)) {
+		t.Errorf("Last-Modified actual=%v expected=%v (uncompressed file's ModTime)", lastModified, uncompressedInfo.ModTime())
+	}
+}
@@ -0,0 +1,27 @@
+package web
+
+import "net/http"
+
+// Handler adapts a function that may fail into an http.Handler. This lets
+// handlers use the error-returning Send* helpers directly instead of
+// handling errors at every call site.
+type Handler struct {
+	Func func(w http.ResponseWriter, r *http.Request) error
+
+	// OnError is called when Func returns a non-nil error. If nil,
+	// DefaultErrorHandler is used.
+	OnError ErrorHandlerFunc
+}
+
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	err := h.Func(w, r)
+	if err == nil {
+		return
+	}
+
+	onError := h.OnError
+	if onError == nil {
+		onError = DefaultErrorHandler
+	}
+	onError(w, r, err)
+}
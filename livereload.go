@@ -0,0 +1,94 @@
+package web
+
+import (
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+var liveReloadUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// liveReloadHub tracks the browsers currently connected to LiveReloadHandler.
+type liveReloadHub struct {
+	mu      sync.Mutex
+	clients map[*websocket.Conn]struct{}
+}
+
+var defaultLiveReloadHub = &liveReloadHub{clients: make(map[*websocket.Conn]struct{})}
+
+func (hub *liveReloadHub) add(conn *websocket.Conn) {
+	hub.mu.Lock()
+	hub.clients[conn] = struct{}{}
+	hub.mu.Unlock()
+}
+
+func (hub *liveReloadHub) remove(conn *websocket.Conn) {
+	hub.mu.Lock()
+	delete(hub.clients, conn)
+	hub.mu.Unlock()
+}
+
+func (hub *liveReloadHub) notify() {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	for conn := range hub.clients {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte("reload")); err != nil {
+			conn.Close()
+			delete(hub.clients, conn)
+		}
+	}
+}
+
+// notifyLiveReload broadcasts a reload message to every client connected to
+// LiveReloadHandler. Renderer.WatchTemplateDirectory and FileStore.Watch
+// call this whenever a watched file changes.
+func notifyLiveReload() {
+	defaultLiveReloadHub.notify()
+}
+
+// LiveReloadHandler returns an http.Handler that upgrades requests to a
+// WebSocket connection and pushes a "reload" message whenever a template
+// watched by Renderer.WatchTemplateDirectory, or an asset watched by
+// FileStore.Watch, changes on disk. Mount it at "/_livereload" so that the
+// script injected by Renderer.InjectLiveReloadScript can find it.
+func LiveReloadHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := liveReloadUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Println("[web] warning: live reload upgrade failed:", err)
+			return
+		}
+
+		defaultLiveReloadHub.add(conn)
+		defer func() {
+			defaultLiveReloadHub.remove(conn)
+			conn.Close()
+		}()
+
+		// The client never sends anything meaningful; read until it
+		// disconnects so we notice and clean up.
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+}
+
+// liveReloadScript is injected before </body> by SendTemplate when
+// Renderer.InjectLiveReloadScript(true) has been called.
+const liveReloadScript = `<script>(function() {
+	var proto = location.protocol === "https:" ? "wss:" : "ws:";
+	var socket = new WebSocket(proto + "//" + location.host + "/_livereload");
+	socket.onmessage = function(event) {
+		if (event.data === "reload") {
+			location.reload();
+		}
+	};
+})();</script>`
@@ -0,0 +1,200 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Encoder encodes value and writes the result to w. Encoders are registered
+// with RegisterEncoder and selected by SendNegotiated based on the
+// request's Accept header.
+type Encoder interface {
+	Encode(w io.Writer, value interface{}) error
+}
+
+// EncoderFunc adapts a function to the Encoder interface.
+type EncoderFunc func(w io.Writer, value interface{}) error
+
+func (f EncoderFunc) Encode(w io.Writer, value interface{}) error {
+	return f(w, value)
+}
+
+var (
+	encodersMutex sync.RWMutex
+	encoders      = map[string]Encoder{
+		"application/json": EncoderFunc(func(w io.Writer, value interface{}) error {
+			return json.NewEncoder(w).Encode(value)
+		}),
+		"application/xml": EncoderFunc(func(w io.Writer, value interface{}) error {
+			return xml.NewEncoder(w).Encode(value)
+		}),
+		"text/plain": EncoderFunc(func(w io.Writer, value interface{}) error {
+			_, err := fmt.Fprint(w, value)
+			return err
+		}),
+	}
+)
+
+// RegisterEncoder registers (or replaces) the Encoder used for mimeType by
+// SendNegotiated.
+func RegisterEncoder(mimeType string, encoder Encoder) {
+	encodersMutex.Lock()
+	defer encodersMutex.Unlock()
+	encoders[mimeType] = encoder
+}
+
+// NegotiatedOptions controls SendNegotiated.
+type NegotiatedOptions struct {
+	// Offers lists the MIME types to negotiate between, in preference
+	// order when the client's Accept header ranks them equally. If empty,
+	// "application/json", "application/xml" and "text/plain" are offered, in
+	// that order; MIME types added with RegisterEncoder are only offered
+	// when listed here.
+	Offers []string
+}
+
+var defaultOffers = []string{"application/json", "application/xml", "text/plain"}
+
+// SendNegotiated answers the request by picking an Encoder based on the
+// request's Accept header and opts.Offers, encoding value with it. It
+// returns a 406 Not Acceptable HTTPError if no offered MIME type is
+// acceptable to the client, or has no registered Encoder.
+func SendNegotiated(w http.ResponseWriter, r *http.Request, statusCode int, value interface{}, opts NegotiatedOptions) error {
+	offers := opts.Offers
+	if len(offers) == 0 {
+		offers = defaultOffers
+	}
+
+	mimeType := negotiateContentType(r.Header.Get("Accept"), offers)
+	if mimeType == "" {
+		return NewHTTPError(http.StatusNotAcceptable)
+	}
+
+	encodersMutex.RLock()
+	encoder := encoders[mimeType]
+	encodersMutex.RUnlock()
+	if encoder == nil {
+		return NewHTTPError(http.StatusNotAcceptable)
+	}
+
+	buffer := new(bytes.Buffer)
+	if err := encoder.Encode(buffer, value); err != nil {
+		return err
+	}
+
+	return SendBLOB(w, r, statusCode, mimeType, buffer.Bytes())
+}
+
+// negotiateContentType returns the first of offers that is acceptable
+// according to header (an Accept header value), preferring offers in the
+// order the client ranked them and falling back to the order of offers
+// itself to break ties. It returns "" if header is non-empty and none of
+// offers is acceptable.
+func negotiateContentType(header string, offers []string) string {
+	if strings.TrimSpace(header) == "" {
+		return offers[0]
+	}
+
+	ranges := parseAcceptMediaRanges(header)
+	best := ""
+	bestQuality := 0.0
+	bestSpecificity := -1
+	for _, offer := range offers {
+		// An offer's quality comes from the most specific range matching
+		// it, so "application/json;q=0, */*" still excludes JSON.
+		quality, specificity := 0.0, -1
+		for _, rng := range ranges {
+			if !mediaRangeMatches(rng.mimeType, offer) {
+				continue
+			}
+			if s := mediaRangeSpecificity(rng.mimeType); s > specificity {
+				quality, specificity = rng.quality, s
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+		if quality > bestQuality || (quality == bestQuality && specificity > bestSpecificity) {
+			best = offer
+			bestQuality = quality
+			bestSpecificity = specificity
+		}
+	}
+	return best
+}
+
+type mediaRange struct {
+	mimeType string
+	quality  float64
+}
+
+// parseAcceptMediaRanges parses an Accept header into its media ranges and
+// their q-values, defaulting to q=1 when not specified.
+func parseAcceptMediaRanges(header string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		mimeType := part
+		quality := 1.0
+		if i := strings.Index(part, ";"); i >= 0 {
+			mimeType = strings.TrimSpace(part[:i])
+			if qs := strings.TrimSpace(part[i+1:]); strings.HasPrefix(qs, "q=") {
+				if v, err := strconv.ParseFloat(strings.TrimPrefix(qs, "q="), 64); err == nil {
+					quality = v
+				}
+			}
+		}
+
+		ranges = append(ranges, mediaRange{mimeType: strings.ToLower(mimeType), quality: quality})
+	}
+	return ranges
+}
+
+// mediaRangeMatches reports whether offer (a concrete MIME type) satisfies
+// pattern, which may contain "*/*" or "type/*" wildcards.
+func mediaRangeMatches(pattern, offer string) bool {
+	if pattern == "*/*" {
+		return true
+	}
+
+	patternType, patternSubtype, ok := strings.Cut(pattern, "/")
+	if !ok {
+		return false
+	}
+	offerType, offerSubtype, ok := strings.Cut(offer, "/")
+	if !ok {
+		return false
+	}
+
+	if patternType != offerType {
+		return false
+	}
+	return patternSubtype == "*" || patternSubtype == offerSubtype
+}
+
+// mediaRangeSpecificity ranks a media range by how specific it is: an exact
+// type/subtype match outranks a type/* wildcard, which outranks */*.
+// negotiateContentType uses it to pick which of several matching ranges
+// decides an offer's quality, and to break ties between offers of equal
+// quality.
+func mediaRangeSpecificity(pattern string) int {
+	switch {
+	case pattern == "*/*":
+		return 0
+	case strings.HasSuffix(pattern, "/*"):
+		return 1
+	default:
+		return 2
+	}
+}
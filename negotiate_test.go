@@ -0,0 +1,71 @@
+package web
+
+import "testing"
+
+func TestNegotiateContentType(t *testing.T) {
+	offers := []string{"application/json", "application/xml", "text/plain"}
+
+	testCases := []struct {
+		name     string
+		header   string
+		offers   []string
+		expected string
+	}{
+		{"no header picks first offer", "", offers, "application/json"},
+		{"exact match", "application/xml", offers, "application/xml"},
+		{"wildcard subtype", "application/*", offers, "application/json"},
+		{"catch-all wildcard", "*/*", offers, "application/json"},
+		{"q=0 excludes, falls through", "application/json;q=0, application/xml", offers, "application/xml"},
+		{"specific beats wildcard at equal quality", "*/*, text/plain", offers, "text/plain"},
+		{"nothing acceptable", "image/png", offers, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := negotiateContentType(tc.header, tc.offers)
+			if actual != tc.expected {
+				t.Errorf("actual=%q expected=%q", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestMediaRangeMatches(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		offer    string
+		expected bool
+	}{
+		{"*/*", "application/json", true},
+		{"application/*", "application/json", true},
+		{"application/*", "text/plain", false},
+		{"application/json", "application/json", true},
+		{"application/json", "application/xml", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.pattern+" vs "+tc.offer, func(t *testing.T) {
+			actual := mediaRangeMatches(tc.pattern, tc.offer)
+			if actual != tc.expected {
+				t.Errorf("actual=%v expected=%v", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestMediaRangeSpecificity(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		expected int
+	}{
+		{"*/*", 0},
+		{"application/*", 1},
+		{"application/json", 2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.pattern, func(t *testing.T) {
+			actual := mediaRangeSpecificity(tc.pattern)
+			if actual != tc.expected {
+				t.Errorf("actual=%d expected=%d", actual, tc.expected)
+			}
+		})
+	}
+}
@@ -1,16 +1,18 @@
 package web
 
 import (
+	"fmt"
 	"io"
 	"io/fs"
 	"log"
 	"os"
 	"path"
+	"path/filepath"
+	"strings"
+	"sync"
 
 	htmltemplate "html/template"
 	texttemplate "text/template"
-
-	"github.com/fsnotify/fsnotify"
 )
 
 type FuncMap map[string]interface{}
@@ -22,21 +24,100 @@ type Renderer struct {
 	// When watching the template directory, we want to parse and execute
 	// in parallel, so we have to keep a clean base copy of the template
 	// for parsing and the regular template which is used for executing.
-	textbase      *texttemplate.Template
-	texttemplates *texttemplate.Template
+	//
+	// templatesMutex guards textbase/texttemplates/htmlbase/htmltemplates
+	// (and textfiles/htmlfiles below), since WatchTemplateDirectory
+	// reloads and reassigns them from its own goroutine while Render reads
+	// them from request-handling goroutines.
+	templatesMutex sync.RWMutex
+	textbase       *texttemplate.Template
+	texttemplates  *texttemplate.Template
 
 	htmlbase      *htmltemplate.Template
 	htmltemplates *htmltemplate.Template
+
+	// layouts holds the names (as passed to RendererOptions.Layouts) of the
+	// templates that every page is combined with. The last entry is the
+	// outermost layout, the one actually executed.
+	layouts []string
+
+	pagesMutex sync.RWMutex
+	textPages  map[string]*texttemplate.Template
+	htmlPages  map[string]*htmltemplate.Template
+
+	// textfiles and htmlfiles remember every discovered template name, so
+	// that WatchTemplateDirectory can rebuild the layout pages when a
+	// layout or page changes.
+	textfiles []string
+	htmlfiles []string
+	functions FuncMap
+
+	sourcesMutex sync.Mutex
+	sources      map[string]string // template name -> raw source, for ErrorHandler
+
+	// Debug enables ErrorHandler to render a diagnostic HTML page showing
+	// the failing template, instead of letting the error pass through
+	// unchanged. It should be off in production.
+	Debug bool
+
+	// injectLiveReload is set via InjectLiveReloadScript.
+	injectLiveReload bool
 }
 
+// InjectLiveReloadScript enables or disables injecting a small script tag
+// before </body> in HTML produced by SendTemplate. The script connects to
+// LiveReloadHandler (expected to be mounted at "/_livereload") and reloads
+// the page whenever it is notified. It should be off in production.
+func (r *Renderer) InjectLiveReloadScript(enabled bool) {
+	r.injectLiveReload = enabled
+}
+
+// Render executes the named template, writing its output to w.
+//
+// If RendererOptions.Layouts was non-empty and name identifies a page
+// rather than a layout, the outermost layout is executed instead, with the
+// page's own definitions (such as a {{define "content"}} block) available
+// to it.
 func (r *Renderer) Render(w io.Writer, name string, data interface{}) error {
 	if isText(name) {
-		return r.texttemplates.ExecuteTemplate(w, name, data)
+		if tmpl, ok := r.textPage(name); ok {
+			return tmpl.ExecuteTemplate(w, r.layouts[len(r.layouts)-1], data)
+		}
+		return r.texttemplatesSnapshot().ExecuteTemplate(w, name, data)
 	} else {
-		return r.htmltemplates.ExecuteTemplate(w, name, data)
+		if tmpl, ok := r.htmlPage(name); ok {
+			return tmpl.ExecuteTemplate(w, r.layouts[len(r.layouts)-1], data)
+		}
+		return r.htmltemplatesSnapshot().ExecuteTemplate(w, name, data)
 	}
 }
 
+func (r *Renderer) texttemplatesSnapshot() *texttemplate.Template {
+	r.templatesMutex.RLock()
+	defer r.templatesMutex.RUnlock()
+	return r.texttemplates
+}
+
+func (r *Renderer) htmltemplatesSnapshot() *htmltemplate.Template {
+	r.templatesMutex.RLock()
+	defer r.templatesMutex.RUnlock()
+	return r.htmltemplates
+}
+
+func (r *Renderer) textPage(name string) (*texttemplate.Template, bool) {
+	r.pagesMutex.RLock()
+	defer r.pagesMutex.RUnlock()
+	tmpl, ok := r.textPages[name]
+	return tmpl, ok
+}
+
+func (r *Renderer) htmlPage(name string) (*htmltemplate.Template, bool) {
+	r.pagesMutex.RLock()
+	defer r.pagesMutex.RUnlock()
+	tmpl, ok := r.htmlPages[name]
+	return tmpl, ok
+}
+
 type RendererOptions struct {
 	// Fsys specifies the file system to use.
 	// If Fsys is nil, os.DirFS(".") is used.
@@ -49,17 +130,34 @@ type RendererOptions struct {
 	// Functions is a map of functions to pass to the templates.
 	// Can be nil, if there are no functions.
 	Functions FuncMap
+
+	// Layouts lists the names (path-style, relative to Directory, e.g.
+	// "layouts/base.html") of templates that are combined with every other
+	// ("page") template, so a page can provide a {{define "content"}}
+	// block that the layout renders inside itself. List nested layouts
+	// before the layout that uses them; the last entry is the one actually
+	// executed for every page. Leave empty to render each template as-is.
+	Layouts []string
+
+	// Debug enables ErrorHandler to render a diagnostic HTML page with
+	// source context when a template fails to render. It should be off
+	// in production.
+	Debug bool
 }
 
 // NewRenderer parses the templates from the given file system and directory.
 //
-// Subdirectories are not supported at the moment, because the template
-// package identifies templates by filename alone.
+// Templates may live in subdirectories; each is registered under its
+// slash-separated path relative to Directory (e.g. "admin/users/list.html"),
+// and Render/SendTemplate accept that same path-style name.
 //
 // The text/template package is used for files ending in .text.ext.
 // All other files are handled by the html/template package.
 func NewRenderer(options RendererOptions) *Renderer {
 	r := new(Renderer)
+	r.Debug = options.Debug
+	r.layouts = options.Layouts
+	r.sources = make(map[string]string)
 
 	if options.Directory == "" {
 		options.Directory = "."
@@ -70,101 +168,256 @@ func NewRenderer(options RendererOptions) *Renderer {
 		r.directory = options.Directory
 	}
 
-	textfiles, htmlfiles, err := readFiles(options.Fsys, options.Directory)
+	textfiles, htmlfiles, err := walkFiles(options.Fsys, options.Directory)
 	if err != nil {
 		log.Println("[renderer] error: failed to read template directory:", err)
 		// Do not return. The code below creates empty templates.
 	}
+	r.textfiles = textfiles
+	r.htmlfiles = htmlfiles
+	r.functions = options.Functions
+
+	r.recordSources(options.Fsys, options.Directory, textfiles, htmlfiles)
 
 	r.textbase = texttemplate.New("").Funcs(texttemplate.FuncMap(options.Functions))
-	if len(textfiles) != 0 {
-		r.textbase = texttemplate.Must(r.textbase.ParseFS(options.Fsys, textfiles...))
+	for _, name := range textfiles {
+		parseText(r.textbase, options.Fsys, options.Directory, name)
 	}
 	r.texttemplates = texttemplate.Must(r.textbase.Clone())
 
 	r.htmlbase = htmltemplate.New("").Funcs(htmltemplate.FuncMap(options.Functions))
-	if len(htmlfiles) != 0 {
-		r.htmlbase = htmltemplate.Must(r.htmlbase.ParseFS(options.Fsys, htmlfiles...))
+	for _, name := range htmlfiles {
+		parseHTML(r.htmlbase, options.Fsys, options.Directory, name)
 	}
 	r.htmltemplates = htmltemplate.Must(r.htmlbase.Clone())
 
+	if len(r.layouts) != 0 {
+		r.textPages = buildTextPages(r.textbase, options.Fsys, options.Directory, textfiles, r.layouts, texttemplate.FuncMap(options.Functions))
+		r.htmlPages = buildHTMLPages(r.htmlbase, options.Fsys, options.Directory, htmlfiles, r.layouts, htmltemplate.FuncMap(options.Functions))
+	}
+
 	return r
 }
 
-func readFiles(fsys fs.FS, directory string) (textfiles []string, htmlfiles []string, err error) {
-	infos, err := fs.ReadDir(fsys, directory)
+// parseText reads name (relative to directory, in fsys) and associates it
+// with base under the path-style template name name, using the
+// New(name).Parse(...) pattern so that subdirectories work.
+func parseText(base *texttemplate.Template, fsys fs.FS, directory, name string) {
+	contents, err := fs.ReadFile(fsys, path.Join(directory, name))
+	if err != nil {
+		log.Println("[renderer] warning: failed to read template file:", name, err)
+		return
+	}
+	_, err = base.New(name).Parse(string(contents))
+	if err != nil {
+		log.Println("[renderer] warning: failed to parse template file:", name, err)
+	}
+}
+
+func parseHTML(base *htmltemplate.Template, fsys fs.FS, directory, name string) {
+	contents, err := fs.ReadFile(fsys, path.Join(directory, name))
 	if err != nil {
-		return nil, nil, err
+		log.Println("[renderer] warning: failed to read template file:", name, err)
+		return
 	}
+	_, err = base.New(name).Parse(string(contents))
+	if err != nil {
+		log.Println("[renderer] warning: failed to parse template file:", name, err)
+	}
+}
 
-	for _, info := range infos {
-		if info.IsDir() {
+// buildTextPages parses every non-layout file in isolation and combines it
+// with a clone of base (which at this point holds every template,
+// including the layouts) via AddParseTree, so that same-named {{define}}
+// blocks in different pages do not collide with each other.
+func buildTextPages(base *texttemplate.Template, fsys fs.FS, directory string, files, layouts []string, functions texttemplate.FuncMap) map[string]*texttemplate.Template {
+	pages := make(map[string]*texttemplate.Template)
+	for _, name := range files {
+		if contains(layouts, name) {
 			continue
 		}
-		filename := path.Join(directory, info.Name())
-		if isText(filename) {
-			textfiles = append(textfiles, filename)
-		} else {
-			htmlfiles = append(htmlfiles, filename)
+
+		contents, err := fs.ReadFile(fsys, path.Join(directory, name))
+		if err != nil {
+			continue
 		}
+
+		page, err := texttemplate.New(name).Funcs(functions).Parse(string(contents))
+		if err != nil {
+			log.Println("[renderer] warning: failed to parse template file:", name, err)
+			continue
+		}
+
+		clone, err := base.Clone()
+		if err != nil {
+			log.Println("[renderer] warning: failed to clone layouts for template file:", name, err)
+			continue
+		}
+		for _, tmpl := range page.Templates() {
+			if _, err := clone.AddParseTree(tmpl.Name(), tmpl.Tree); err != nil {
+				log.Println("[renderer] warning: failed to associate template file with layouts:", name, err)
+			}
+		}
+
+		pages[name] = clone
 	}
+	return pages
+}
+
+func buildHTMLPages(base *htmltemplate.Template, fsys fs.FS, directory string, files, layouts []string, functions htmltemplate.FuncMap) map[string]*htmltemplate.Template {
+	pages := make(map[string]*htmltemplate.Template)
+	for _, name := range files {
+		if contains(layouts, name) {
+			continue
+		}
 
+		contents, err := fs.ReadFile(fsys, path.Join(directory, name))
+		if err != nil {
+			continue
+		}
+
+		page, err := htmltemplate.New(name).Funcs(functions).Parse(string(contents))
+		if err != nil {
+			log.Println("[renderer] warning: failed to parse template file:", name, err)
+			continue
+		}
+
+		clone, err := base.Clone()
+		if err != nil {
+			log.Println("[renderer] warning: failed to clone layouts for template file:", name, err)
+			continue
+		}
+		for _, tmpl := range page.Templates() {
+			if _, err := clone.AddParseTree(tmpl.Name(), tmpl.Tree); err != nil {
+				log.Println("[renderer] warning: failed to associate template file with layouts:", name, err)
+			}
+		}
+
+		pages[name] = clone
+	}
+	return pages
+}
+
+func contains(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+	return false
+}
+
+// recordSources caches the raw contents of the given template files, keyed
+// by their path-style template name, so that ErrorHandler can show source
+// context for failures.
+func (r *Renderer) recordSources(fsys fs.FS, directory string, filenames ...[]string) {
+	r.sourcesMutex.Lock()
+	defer r.sourcesMutex.Unlock()
+
+	for _, group := range filenames {
+		for _, name := range group {
+			contents, err := fs.ReadFile(fsys, path.Join(directory, name))
+			if err != nil {
+				continue
+			}
+			r.sources[name] = string(contents)
+		}
+	}
+}
+
+// walkFiles finds every file under directory in fsys, returning its path
+// relative to directory, split into text and html templates.
+func walkFiles(fsys fs.FS, directory string) (textfiles []string, htmlfiles []string, err error) {
+	err = fs.WalkDir(fsys, directory, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		relPath := strings.TrimPrefix(p, directory+"/")
+
+		if isText(relPath) {
+			textfiles = append(textfiles, relPath)
+		} else {
+			htmlfiles = append(htmlfiles, relPath)
+		}
+		return nil
+	})
 	return
 }
 
-// Only works if the renderer has been created with options.Fsys == nil.
-func (r *Renderer) WatchTemplateDirectory() {
+// WatchTemplateDirectory watches the template directory (recursively) for
+// changes and reloads templates as they are edited, also notifying any
+// clients connected to LiveReloadHandler. It only works if the renderer was
+// created with options.Fsys == nil. The returned io.Closer stops watching
+// and must be closed once the renderer is no longer needed.
+func (r *Renderer) WatchTemplateDirectory() (io.Closer, error) {
 	if r.directory == "" {
-		return
+		return nil, fmt.Errorf("renderer: WatchTemplateDirectory requires a renderer created with options.Fsys == nil")
 	}
 
-	watcher, err := fsnotify.NewWatcher() // @Leak: Close watcher.
+	return watchDirectory(r.directory, func(name string) {
+		relPath := strings.TrimPrefix(filepath.ToSlash(name), filepath.ToSlash(r.directory)+"/")
+
+		if err := r.reloadFile(relPath); err != nil {
+			log.Printf("[renderer] warning: failed to reload template file: %s: %v", name, err)
+			return
+		}
+
+		log.Println("[renderer] info: updated template file:", name)
+		notifyLiveReload()
+	})
+}
+
+// reloadFile re-parses the template named relPath (path-style, relative to
+// r.directory) into the shared base template, refreshes its cached source,
+// and, if layouts are configured, rebuilds the per-page layout clones.
+func (r *Renderer) reloadFile(relPath string) error {
+	fsys := os.DirFS(r.directory)
+
+	contents, err := fs.ReadFile(fsys, relPath)
 	if err != nil {
-		log.Println("[renderer] warning: failed to create template watcher:", err)
-		return
+		return err
 	}
 
-	err = watcher.Add(r.directory)
-	if err != nil {
-		log.Println("[renderer] warning: failed to watch template directory:", err)
-		return
+	r.templatesMutex.Lock()
+	if isText(relPath) {
+		if _, err := r.textbase.New(relPath).Parse(string(contents)); err != nil {
+			r.templatesMutex.Unlock()
+			return err
+		}
+		r.texttemplates = texttemplate.Must(r.textbase.Clone())
+		if !contains(r.textfiles, relPath) {
+			r.textfiles = append(r.textfiles, relPath)
+		}
+	} else {
+		if _, err := r.htmlbase.New(relPath).Parse(string(contents)); err != nil {
+			r.templatesMutex.Unlock()
+			return err
+		}
+		r.htmltemplates = htmltemplate.Must(r.htmlbase.Clone())
+		if !contains(r.htmlfiles, relPath) {
+			r.htmlfiles = append(r.htmlfiles, relPath)
+		}
 	}
+	textbase, htmlbase, textfiles, htmlfiles := r.textbase, r.htmlbase, r.textfiles, r.htmlfiles
+	r.templatesMutex.Unlock()
 
-	go func() {
-		for {
-			select {
-			case event := <-watcher.Events:
-				if event.Op&fsnotify.Write != 0 {
-					var err error
-
-					if isText(event.Name) {
-						var updated *texttemplate.Template
-						updated, err = r.textbase.ParseFiles(event.Name)
-						if err == nil {
-							r.textbase = updated
-							r.texttemplates = texttemplate.Must(updated.Clone())
-						}
-					} else {
-						var updated *htmltemplate.Template
-						updated, err = r.htmlbase.ParseFiles(event.Name)
-						if err == nil {
-							r.htmlbase = updated
-							r.htmltemplates = htmltemplate.Must(updated.Clone())
-						}
-					}
-
-					if err != nil {
-						log.Printf("[renderer] warning: failed to reload template file: %s: %v", event.Name, err)
-					} else {
-						log.Println("[renderer] info: updated template file:", event.Name)
-					}
-				}
+	r.recordSources(fsys, "", []string{relPath})
 
-			case err := <-watcher.Errors:
-				log.Println("[renderer] warning: template watcher error:", err)
-			}
-		}
-	}()
+	if len(r.layouts) != 0 {
+		textPages := buildTextPages(textbase, fsys, "", textfiles, r.layouts, texttemplate.FuncMap(r.functions))
+		htmlPages := buildHTMLPages(htmlbase, fsys, "", htmlfiles, r.layouts, htmltemplate.FuncMap(r.functions))
+
+		r.pagesMutex.Lock()
+		r.textPages = textPages
+		r.htmlPages = htmlPages
+		r.pagesMutex.Unlock()
+	}
+
+	return nil
 }
 
 func isText(filename string) bool {
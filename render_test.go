@@ -1,7 +1,11 @@
 package web
 
 import (
+	"bytes"
+	htmltemplate "html/template"
+	"sort"
 	"testing"
+	"testing/fstest"
 )
 
 func TestExt2(t *testing.T) {
@@ -23,3 +27,80 @@ func TestExt2(t *testing.T) {
 		})
 	}
 }
+
+func TestWalkFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"templates/index.html":                 {Data: []byte("index")},
+		"templates/admin/users/list.html":      {Data: []byte("list")},
+		"templates/admin/users/edit.text.html": {Data: []byte("edit")},
+	}
+
+	textfiles, htmlfiles, err := walkFiles(fsys, "templates")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sort.Strings(textfiles)
+	sort.Strings(htmlfiles)
+
+	expectedText := []string{"admin/users/edit.text.html"}
+	expectedHTML := []string{"admin/users/list.html", "index.html"}
+
+	if !equalStringSlices(textfiles, expectedText) {
+		t.Errorf("textfiles actual=%v expected=%v", textfiles, expectedText)
+	}
+	if !equalStringSlices(htmlfiles, expectedHTML) {
+		t.Errorf("htmlfiles actual=%v expected=%v", htmlfiles, expectedHTML)
+	}
+}
+
+func equalStringSlices(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBuildHTMLPagesIsolatesSameNamedBlocks(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layout.html": {Data: []byte(`<html>{{template "content" .}}</html>`)},
+		"a.html":      {Data: []byte(`{{define "content"}}A{{end}}`)},
+		"b.html":      {Data: []byte(`{{define "content"}}B{{end}}`)},
+	}
+	layouts := []string{"layout.html"}
+
+	base := htmltemplate.New("")
+	for _, name := range layouts {
+		contents, err := fsys.ReadFile(name)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if _, err := base.New(name).Parse(string(contents)); err != nil {
+			t.Fatalf("failed to parse %s: %v", name, err)
+		}
+	}
+
+	pages := buildHTMLPages(base, fsys, "", []string{"a.html", "b.html"}, layouts, nil)
+	if len(pages) != 2 {
+		t.Fatalf("expected 2 pages, got %d", len(pages))
+	}
+
+	for name, want := range map[string]string{"a.html": "<html>A</html>", "b.html": "<html>B</html>"} {
+		page, ok := pages[name]
+		if !ok {
+			t.Fatalf("missing page %s", name)
+		}
+		var buf bytes.Buffer
+		if err := page.ExecuteTemplate(&buf, "layout.html", nil); err != nil {
+			t.Fatalf("failed to execute %s: %v", name, err)
+		}
+		if buf.String() != want {
+			t.Errorf("page=%s actual=%q expected=%q", name, buf.String(), want)
+		}
+	}
+}
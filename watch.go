@@ -0,0 +1,91 @@
+package web
+
+import (
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchRelevantOps are the fsnotify operations that indicate a file's
+// contents may have changed on disk. Many editors save by writing a
+// temporary file and renaming it into place, which shows up as Create (and
+// sometimes Rename) rather than Write, so all three are treated the same.
+const watchRelevantOps = fsnotify.Write | fsnotify.Create | fsnotify.Rename
+
+// watchDirectory recursively watches directory for changes, calling
+// onChange with the path of every file that is created, written, or
+// renamed into place (directories themselves are watched automatically but
+// never passed to onChange). It returns an io.Closer that stops the watch.
+func watchDirectory(directory string, onChange func(name string)) (io.Closer, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	err = filepath.WalkDir(directory, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return watcher.Add(p)
+		}
+		return nil
+	})
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op&watchRelevantOps == 0 {
+					continue
+				}
+
+				info, err := os.Stat(event.Name)
+				if err == nil && info.IsDir() {
+					// A new subdirectory was created; start watching it too.
+					if event.Op&fsnotify.Create != 0 {
+						if err := watcher.Add(event.Name); err != nil {
+							log.Println("[web] warning: failed to watch new directory:", event.Name, err)
+						}
+					}
+					continue
+				}
+
+				onChange(event.Name)
+
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Println("[web] warning: watcher error:", err)
+
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return &watchCloser{watcher: watcher, done: done}, nil
+}
+
+type watchCloser struct {
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+}
+
+func (c *watchCloser) Close() error {
+	close(c.done)
+	return c.watcher.Close()
+}
@@ -0,0 +1,79 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVariantBaseName(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"foo.js", "foo.js"},
+		{"foo.js.gz", "foo.js"},
+		{"foo.js.br", "foo.js"},
+		{"foo.js.zst", "foo.js"},
+		{"dir/foo.css.gz", "dir/foo.css"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			actual := variantBaseName(tc.input)
+			if actual != tc.expected {
+				t.Errorf("actual=%s expected=%s", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestFileStoreWatchInvalidatesVariantsOnCompressedSibling(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "foo.js"), []byte("console.log(1)"), 0o644); err != nil {
+		t.Fatalf("failed to write foo.js: %v", err)
+	}
+
+	store := NewFileStoreFromDirectory(dir)
+	store.PrecompressedAssets = true
+
+	sendFile := func(acceptEncoding string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/foo.js", nil)
+		if acceptEncoding != "" {
+			r.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		if err := store.SendFile(w, r, "foo.js"); err != nil {
+			t.Fatalf("SendFile failed: %v", err)
+		}
+		return w
+	}
+
+	// Negatively caches "no variants" for foo.js.
+	if enc := sendFile("gzip").Header().Get("Content-Encoding"); enc != "" {
+		t.Fatalf("expected no Content-Encoding before any variant exists, got %q", enc)
+	}
+
+	closer, err := store.Watch()
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	defer closer.Close()
+
+	if err := os.WriteFile(filepath.Join(dir, "foo.js.gz"), []byte("gzipped"), 0o644); err != nil {
+		t.Fatalf("failed to write foo.js.gz: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if enc := sendFile("gzip").Header().Get("Content-Encoding"); enc == "gzip" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for watcher to invalidate the variant cache for foo.js.gz")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
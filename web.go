@@ -51,7 +51,29 @@ func SendTemplate(w http.ResponseWriter, r *http.Request, statusCode int, render
 	if err != nil {
 		return err
 	}
-	return SendBLOB(w, r, statusCode, "text/html; charset=UTF-8", buffer.Bytes())
+
+	body := buffer.Bytes()
+	if renderer.injectLiveReload {
+		body = injectLiveReloadScript(body)
+	}
+
+	return SendBLOB(w, r, statusCode, "text/html; charset=UTF-8", body)
+}
+
+// injectLiveReloadScript inserts liveReloadScript just before the last
+// </body> tag in body, or returns body unchanged if it has none.
+func injectLiveReloadScript(body []byte) []byte {
+	marker := []byte("</body>")
+	index := bytes.LastIndex(body, marker)
+	if index < 0 {
+		return body
+	}
+
+	out := make([]byte, 0, len(body)+len(liveReloadScript))
+	out = append(out, body[:index]...)
+	out = append(out, []byte(liveReloadScript)...)
+	out = append(out, body[index:]...)
+	return out
 }
 
 func SendJSON(w http.ResponseWriter, r *http.Request, statusCode int, value interface{}) error {
@@ -81,3 +103,40 @@ func SendBLOB(w http.ResponseWriter, r *http.Request, statusCode int, contentTyp
 	}
 	return nil
 }
+
+// StreamJSON answers the request by encoding value directly to w as JSON,
+// unlike SendJSON, which marshals into memory first. This avoids a full
+// duplicate allocation for large values, at the cost of no longer being
+// able to recover from an encoding error after the status code has been
+// written.
+func StreamJSON(w http.ResponseWriter, r *http.Request, statusCode int, value interface{}) error {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		// Do not return this error, as we have already committed the response.
+		log.Println("[web] error: failed to stream JSON:", err)
+	}
+	return nil
+}
+
+// StreamTemplate renders name directly to w, unlike SendTemplate, which
+// buffers the output into memory first. Because the status code has
+// already been written by the time the template executes, a render error
+// can no longer change it, so pass buffer=true to fall back to
+// SendTemplate's buffered behavior whenever that matters more than avoiding
+// the allocation (e.g. so a failing template can still produce a 500).
+// StreamTemplate also falls back to SendTemplate, regardless of buffer,
+// while renderer has InjectLiveReloadScript enabled.
+func StreamTemplate(w http.ResponseWriter, r *http.Request, statusCode int, renderer *Renderer, name string, data interface{}, buffer bool) error {
+	if buffer || renderer.injectLiveReload {
+		return SendTemplate(w, r, statusCode, renderer, name, data)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.WriteHeader(statusCode)
+	if err := renderer.Render(w, name, data); err != nil {
+		// Do not return this error, as we have already committed the response.
+		log.Println("[web] error: failed to stream template:", name, err)
+	}
+	return nil
+}